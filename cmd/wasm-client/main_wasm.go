@@ -0,0 +1,294 @@
+// +build js
+
+package main
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/jessevdk/go-flags"
+	"github.com/lightninglabs/lightning-node-connect/registry"
+	"github.com/lightningnetwork/lnd/build"
+	"github.com/lightningnetwork/lnd/signal"
+	"github.com/teamortix/golang-wasm/wasm"
+)
+
+var (
+	cfg = config{}
+
+	reg = registry.New(mailboxRPCConnection)
+
+	metricsSubsMtx sync.Mutex
+	metricsSubs    = make(map[string]chan struct{})
+)
+
+func main() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Debugf("Recovered in f: %v", r)
+			debug.PrintStack()
+		}
+	}()
+
+	wasm.Expose("isReady", wasmClientIsReady)
+	wasm.Expose("connectServer", wasmClientConnectServer)
+	wasm.Expose("isConnected", wasmClientIsConnected)
+	wasm.Expose("disconnect", wasmClientDisconnect)
+	wasm.Expose("reconnect", wasmClientReconnect)
+	wasm.Expose("invokeRPC", wasmClientInvokeRPC)
+	wasm.Expose("cancelRPC", wasmClientCancelRPC)
+	wasm.Expose("pauseRPC", wasmClientPauseRPC)
+	wasm.Expose("resumeRPC", wasmClientResumeRPC)
+	wasm.Expose("listSessions", wasmClientListSessions)
+	wasm.Expose("subscribeState", wasmClientSubscribeState)
+	wasm.Expose("getMetricsSnapshot", wasmClientGetMetricsSnapshot)
+	wasm.Expose("subscribeMetrics", wasmClientSubscribeMetrics)
+	wasm.Expose("unsubscribeMetrics", wasmClientUnsubscribeMetrics)
+	wasm.Expose("subscribeErrors", wasmClientSubscribeErrors)
+
+	wasm.Ready()
+
+	// Parse command line flags.
+	parser := flags.NewParser(&cfg, flags.Default)
+	parser.SubcommandsOptional = true
+
+	_, err := parser.Parse()
+	if e, ok := err.(*flags.Error); ok && e.Type == flags.ErrHelp {
+		exit(err)
+	}
+	if err != nil {
+		exit(err)
+	}
+	//
+	// 	// Hook interceptor for os signals.
+	shutdownInterceptor, err := signal.Intercept()
+	if err != nil {
+		exit(err)
+	}
+
+	logWriter := build.NewRotatingLogWriter()
+	SetupLoggers(logWriter, shutdownInterceptor)
+
+	err = build.ParseAndSetDebugLevels(cfg.DebugLevel, logWriter)
+	if err != nil {
+		exit(err)
+	}
+
+	log.Debugf("WASM client ready for connecting")
+
+	select {
+	case <-shutdownInterceptor.ShutdownChannel():
+		log.Debugf("Shutting down WASM client")
+		reg.DisconnectAll()
+		log.Debugf("Shutdown of WASM client complete")
+	}
+	<-make(chan bool) // To use anything from Go WASM, the program may not exit.
+}
+
+func wasmClientIsReady() bool {
+	// This will always return true. So as soon as this method is called
+	// successfully the JS part knows the WASM instance is fully started up
+	// and ready to connect.
+	return true
+}
+
+// wasmClientConnectServer opens a new mailbox connection and registers it
+// under a freshly allocated session ID, which is returned to the caller so
+// that every other exposed call can be routed to the right connection.
+func wasmClientConnectServer(mailboxServer string, isDevServer bool, pairingPhrase string) (string, error) {
+	// Disable TLS verification for the REST connections if this is a dev
+	// server.
+	if isDevServer {
+		defaultHttpTransport := http.DefaultTransport.(*http.Transport)
+		defaultHttpTransport.TLSClientConfig = &tls.Config{
+			InsecureSkipVerify: true,
+		}
+	}
+
+	return reg.Connect(mailboxServer, pairingPhrase)
+}
+
+func wasmClientIsConnected(sessionID string) bool {
+	return reg.IsConnected(sessionID)
+}
+
+// wasmClientDisconnect closes and removes the mailbox connection identified
+// by sessionID.
+func wasmClientDisconnect(sessionID string) {
+	if err := reg.Disconnect(sessionID); err != nil {
+		log.Errorf("Error closing RPC connection for session %s: %v",
+			sessionID, err)
+	}
+}
+
+// wasmClientReconnect redials the mailbox server for an existing session,
+// replacing its connection and canceling any RPC calls still in flight on
+// the old one. JS should call this once it detects the mailbox connection
+// was dropped, e.g. after a browser tab wakes up from being backgrounded.
+func wasmClientReconnect(sessionID string) error {
+	return reg.Reconnect(sessionID)
+}
+
+// wasmClientListSessions returns the IDs of all currently registered
+// sessions, allowing the JS side to recover its view of open connections
+// after e.g. a page reload snapshotted the WASM memory.
+func wasmClientListSessions() []string {
+	return reg.ListSessions()
+}
+
+// wasmClientSubscribeState watches the remote node's StateService and fires
+// jsCallback with the state name every time the node moves through LOCKED,
+// UNLOCKING and RPC_ACTIVE, so a JS UI knows when the main lnrpc.Lightning
+// service (as opposed to the WalletUnlocker) is ready to be called.
+func wasmClientSubscribeState(sessionID string, jsCallback func(state string, err error)) error {
+	return reg.SubscribeState(sessionID, jsCallback)
+}
+
+// wasmClientGetMetricsSnapshot returns a JSON blob of the metrics gathered so
+// far, mirroring the kind of stats an lnmetrics-style reporter would scrape.
+func wasmClientGetMetricsSnapshot() (string, error) {
+	snapshotJSON, err := json.Marshal(reg.Metrics.Snapshot())
+	if err != nil {
+		return "", err
+	}
+	return string(snapshotJSON), nil
+}
+
+// wasmClientSubscribeMetrics periodically pushes a metrics snapshot to
+// jsCallback every intervalMs milliseconds, so a browser UI or a headless
+// diagnostic bot can keep scraping the running WASM instance. It returns a
+// subscription ID that must be passed to wasmClientUnsubscribeMetrics to
+// stop the ticker; otherwise it (and its goroutine) lives for the lifetime
+// of the process.
+func wasmClientSubscribeMetrics(intervalMs int, jsCallback func(snapshotJSON string, err error)) (string, error) {
+	if intervalMs <= 0 {
+		return "", errors.New("intervalMs must be greater than zero")
+	}
+
+	subID, err := genSubscriptionID()
+	if err != nil {
+		return "", err
+	}
+
+	stop := make(chan struct{})
+	metricsSubsMtx.Lock()
+	metricsSubs[subID] = stop
+	metricsSubsMtx.Unlock()
+
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				log.Errorf("Recovered from panic in metrics "+
+					"subscription %s: %v", subID, p)
+				debug.PrintStack()
+			}
+		}()
+
+		ticker := time.NewTicker(time.Duration(intervalMs) * time.Millisecond)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				snapshotJSON, err := json.Marshal(reg.Metrics.Snapshot())
+				if err != nil {
+					jsCallback("", err)
+					continue
+				}
+				jsCallback(string(snapshotJSON), nil)
+
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	return subID, nil
+}
+
+// wasmClientUnsubscribeMetrics stops the metrics ticker started by
+// wasmClientSubscribeMetrics for the given subscription ID.
+func wasmClientUnsubscribeMetrics(subID string) {
+	metricsSubsMtx.Lock()
+	stop, ok := metricsSubs[subID]
+	if ok {
+		delete(metricsSubs, subID)
+	}
+	metricsSubsMtx.Unlock()
+
+	if ok {
+		close(stop)
+	}
+}
+
+// wasmClientSubscribeErrors delivers a structured JSON error to jsCallback
+// every time an RPC call panics, so a crash in one generated JSON callback
+// (a bad proto, a nil deref in a subserver stub) doesn't silently kill the
+// WASM instance and every other in-flight RPC with it.
+func wasmClientSubscribeErrors(jsCallback func(errJSON string)) {
+	reg.SubscribeErrors(func(callErr registry.CallError) {
+		errJSON, err := json.Marshal(callErr)
+		if err != nil {
+			log.Errorf("Error marshaling call error: %v", err)
+			return
+		}
+		jsCallback(string(errJSON))
+	})
+}
+
+// wasmClientInvokeRPC starts the named RPC and returns a request ID
+// synchronously so JS can later cancel or pause/resume the call through
+// wasmClientCancelRPC/wasmClientPauseRPC/wasmClientResumeRPC. This matters
+// most for server-streaming calls such as SubscribeInvoices, which would
+// otherwise only ever stop when the whole mailbox connection is torn down.
+func wasmClientInvokeRPC(sessionID string, rpcName string, requestJSON string, jsCallback func(resultJSON string, err error)) interface{} {
+	requestID, err := reg.InvokeRPC(sessionID, rpcName, requestJSON, jsCallback)
+	if err != nil {
+		return wasm.NewPromise(func() (interface{}, error) {
+			return nil, err
+		})
+	}
+	return requestID
+}
+
+// wasmClientCancelRPC cancels a previously started RPC call, stopping any
+// further callback invocations for it.
+func wasmClientCancelRPC(sessionID string, requestID string) {
+	reg.CancelRPC(sessionID, requestID)
+}
+
+// wasmClientPauseRPC stops delivery of further callback invocations for a
+// streaming RPC until wasmClientResumeRPC is called, giving a slow JS
+// consumer a way to apply backpressure instead of being flooded.
+func wasmClientPauseRPC(sessionID string, requestID string) {
+	reg.PauseRPC(sessionID, requestID)
+}
+
+// wasmClientResumeRPC resumes delivery of callback invocations for a call
+// previously paused with wasmClientPauseRPC.
+func wasmClientResumeRPC(sessionID string, requestID string) {
+	reg.ResumeRPC(sessionID, requestID)
+}
+
+// genSubscriptionID creates a random, URL-safe identifier for a metrics
+// subscription.
+func genSubscriptionID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func exit(err error) {
+	log.Debugf("Error running wasm client: %v", err)
+	os.Exit(1)
+}
@@ -0,0 +1,160 @@
+// Package metrics provides a lightweight, dependency-free recorder for the
+// operational stats the WASM client gathers on every RPC it proxies to the
+// remote node. It is intentionally decoupled from any particular transport:
+// the js-target WASM client records into it and ships a JSON snapshot to the
+// browser, while a future non-js build could implement the same Recorder
+// interface and serve the stats over a Prometheus HTTP endpoint instead.
+package metrics
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// latencyBucketsMs are the upper bounds (in milliseconds) of the latency
+// histogram buckets, modeled after a typical Prometheus histogram.
+var latencyBucketsMs = []int64{10, 50, 100, 500, 1000, 5000}
+
+// Recorder is implemented by anything that can observe RPC activity and
+// produce a point-in-time Snapshot of it.
+type Recorder interface {
+	// RecordCall records the outcome of a single RPC call or, for a
+	// streaming RPC, a single message delivered on the stream.
+	RecordCall(rpcName string, latency time.Duration, err error, bytesIn, bytesOut int)
+
+	// RecordStreamOpen marks the start of a long-lived streaming call.
+	RecordStreamOpen()
+
+	// RecordStreamClose marks the end of a long-lived streaming call.
+	RecordStreamClose()
+
+	// RecordReconnect records a mailbox reconnect event.
+	RecordReconnect()
+
+	// RecordHandshake records how long a pairing/handshake took.
+	RecordHandshake(d time.Duration)
+
+	// Snapshot returns a point-in-time copy of the gathered stats.
+	Snapshot() Snapshot
+}
+
+// CallStats holds the aggregated stats for a single RPC method.
+type CallStats struct {
+	Count            int64            `json:"count"`
+	Errors           int64            `json:"errors"`
+	BytesIn          int64            `json:"bytes_in"`
+	BytesOut         int64            `json:"bytes_out"`
+	LatencyBucketsMs map[string]int64 `json:"latency_buckets_ms"`
+}
+
+// Snapshot is a point-in-time view of everything the Recorder has gathered.
+type Snapshot struct {
+	Calls                map[string]*CallStats `json:"calls"`
+	OpenStreams          int64                 `json:"open_streams"`
+	Reconnects           int64                 `json:"reconnects"`
+	HandshakeDurationsMs []int64               `json:"handshake_durations_ms"`
+}
+
+// inMemory is the default Recorder implementation, keeping everything in
+// process memory. It is what the js-target WASM client uses.
+type inMemory struct {
+	mu sync.Mutex
+
+	calls       map[string]*CallStats
+	openStreams int64
+	reconnects  int64
+	handshakes  []int64
+}
+
+// New returns a Recorder that keeps its stats in memory for the lifetime of
+// the process.
+func New() Recorder {
+	return &inMemory{
+		calls: make(map[string]*CallStats),
+	}
+}
+
+func (m *inMemory) RecordCall(rpcName string, latency time.Duration, err error,
+	bytesIn, bytesOut int) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats, ok := m.calls[rpcName]
+	if !ok {
+		stats = &CallStats{
+			LatencyBucketsMs: make(map[string]int64),
+		}
+		m.calls[rpcName] = stats
+	}
+
+	stats.Count++
+	if err != nil {
+		stats.Errors++
+	}
+	stats.BytesIn += int64(bytesIn)
+	stats.BytesOut += int64(bytesOut)
+	stats.LatencyBucketsMs[bucketLabel(latency)]++
+}
+
+func (m *inMemory) RecordStreamOpen() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.openStreams++
+}
+
+func (m *inMemory) RecordStreamClose() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.openStreams--
+}
+
+func (m *inMemory) RecordReconnect() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reconnects++
+}
+
+func (m *inMemory) RecordHandshake(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handshakes = append(m.handshakes, d.Milliseconds())
+}
+
+func (m *inMemory) Snapshot() Snapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	calls := make(map[string]*CallStats, len(m.calls))
+	for name, stats := range m.calls {
+		statsCopy := *stats
+		statsCopy.LatencyBucketsMs = make(map[string]int64, len(stats.LatencyBucketsMs))
+		for bucket, count := range stats.LatencyBucketsMs {
+			statsCopy.LatencyBucketsMs[bucket] = count
+		}
+		calls[name] = &statsCopy
+	}
+
+	handshakes := make([]int64, len(m.handshakes))
+	copy(handshakes, m.handshakes)
+
+	return Snapshot{
+		Calls:                calls,
+		OpenStreams:          m.openStreams,
+		Reconnects:           m.reconnects,
+		HandshakeDurationsMs: handshakes,
+	}
+}
+
+// bucketLabel returns the label of the smallest latency bucket the given
+// duration falls into, or "+Inf" if it exceeds every bucket.
+func bucketLabel(d time.Duration) string {
+	ms := d.Milliseconds()
+	for _, bound := range latencyBucketsMs {
+		if ms <= bound {
+			return strconv.FormatInt(bound, 10)
+		}
+	}
+	return "+Inf"
+}
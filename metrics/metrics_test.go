@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucketLabel(t *testing.T) {
+	tests := []struct {
+		name     string
+		latency  time.Duration
+		expected string
+	}{
+		{"zero", 0, "10"},
+		{"exactly lowest bound", 10 * time.Millisecond, "10"},
+		{"just above lowest bound", 11 * time.Millisecond, "50"},
+		{"exactly mid bound", 500 * time.Millisecond, "500"},
+		{"exactly highest bound", 5000 * time.Millisecond, "5000"},
+		{"above every bound", 5001 * time.Millisecond, "+Inf"},
+		{"way above every bound", time.Minute, "+Inf"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := bucketLabel(test.latency)
+			if got != test.expected {
+				t.Fatalf("bucketLabel(%v) = %q, want %q",
+					test.latency, got, test.expected)
+			}
+		})
+	}
+}
+
+func TestRecordCallAggregatesPerBucket(t *testing.T) {
+	rec := New()
+
+	rec.RecordCall("Foo", 5*time.Millisecond, nil, 10, 20)
+	rec.RecordCall("Foo", 5*time.Millisecond, nil, 10, 20)
+	rec.RecordCall("Foo", 60*time.Millisecond, errNonNil, 1, 1)
+
+	snap := rec.Snapshot()
+	stats, ok := snap.Calls["Foo"]
+	if !ok {
+		t.Fatalf("expected stats for Foo, got none")
+	}
+
+	if stats.Count != 3 {
+		t.Fatalf("expected count 3, got %d", stats.Count)
+	}
+	if stats.Errors != 1 {
+		t.Fatalf("expected 1 error, got %d", stats.Errors)
+	}
+	if stats.LatencyBucketsMs["10"] != 2 {
+		t.Fatalf("expected 2 calls in the 10ms bucket, got %d",
+			stats.LatencyBucketsMs["10"])
+	}
+	if stats.LatencyBucketsMs["100"] != 1 {
+		t.Fatalf("expected 1 call in the 100ms bucket, got %d",
+			stats.LatencyBucketsMs["100"])
+	}
+}
+
+var errNonNil = &testError{}
+
+type testError struct{}
+
+func (*testError) Error() string { return "boom" }
@@ -0,0 +1,157 @@
+// Package mobile exposes lightning-node-connect to gomobile-generated
+// iOS/Android bindings. It wraps the same registry.Registry the WASM build
+// uses, but through a falafel-style API: exported methods taking []byte
+// requests and a Callback interface instead of JS closures, since gomobile
+// bindings can't export Go channels, maps or function values.
+package mobile
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/lightninglabs/lightning-node-connect/registry"
+)
+
+// Callback is implemented by the host platform (Swift/Kotlin via the
+// generated bindings) to receive the result of an asynchronous call.
+type Callback interface {
+	OnResponse(response []byte)
+	OnError(err string)
+}
+
+// LndMobile is the gomobile-bindable entrypoint into lightning-node-connect.
+// It owns no networking code of its own; dial is supplied by the Go code
+// that constructs it with NewLndMobile, mirroring the role
+// mailboxRPCConnection plays for the WASM build.
+type LndMobile struct {
+	reg *registry.Registry
+}
+
+// NewLndMobile creates an LndMobile that dials new mailbox connections with
+// dial. Since dial is a function value, NewLndMobile is not part of the
+// generated bindings and has to be called from Go.
+func NewLndMobile(dial registry.DialFunc) *LndMobile {
+	return &LndMobile{
+		reg: registry.New(dial),
+	}
+}
+
+// ConnectServer opens a new mailbox connection and returns the allocated
+// session ID, which every other method is routed by.
+func (l *LndMobile) ConnectServer(mailboxServer string, pairingPhrase string) (string, error) {
+	return l.reg.Connect(mailboxServer, pairingPhrase)
+}
+
+// IsConnected reports whether sessionID refers to a registered session; it
+// does not check whether the session's mailbox connection is still up.
+func (l *LndMobile) IsConnected(sessionID string) bool {
+	return l.reg.IsConnected(sessionID)
+}
+
+// Disconnect closes and removes the mailbox connection identified by
+// sessionID.
+func (l *LndMobile) Disconnect(sessionID string) error {
+	return l.reg.Disconnect(sessionID)
+}
+
+// Reconnect redials the mailbox server for an existing session, replacing
+// its connection and canceling any RPC calls still in flight on the old
+// one. The host app should call this once it detects the mailbox
+// connection was dropped, e.g. after a mobile device comes back online.
+func (l *LndMobile) Reconnect(sessionID string) error {
+	return l.reg.Reconnect(sessionID)
+}
+
+// ListSessions returns the IDs of all currently registered sessions.
+func (l *LndMobile) ListSessions() *StringList {
+	return &StringList{items: l.reg.ListSessions()}
+}
+
+// InvokeRPC starts the named RPC and returns a request ID synchronously so
+// the host app can later cancel or pause/resume the call. cb.OnResponse is
+// invoked once per response message; for streaming RPCs it is invoked once
+// per streamed message.
+func (l *LndMobile) InvokeRPC(sessionID string, rpcName string, request []byte, cb Callback) (string, error) {
+	requestID, err := l.reg.InvokeRPC(
+		sessionID, rpcName, string(request),
+		func(resultJSON string, err error) {
+			if err != nil {
+				cb.OnError(err.Error())
+				return
+			}
+			cb.OnResponse([]byte(resultJSON))
+		},
+	)
+	if err != nil {
+		return "", err
+	}
+	return requestID, nil
+}
+
+// CancelRPC cancels a previously started RPC call.
+func (l *LndMobile) CancelRPC(sessionID string, requestID string) {
+	l.reg.CancelRPC(sessionID, requestID)
+}
+
+// PauseRPC stops delivery of further callback invocations for a streaming
+// RPC until ResumeRPC is called.
+func (l *LndMobile) PauseRPC(sessionID string, requestID string) {
+	l.reg.PauseRPC(sessionID, requestID)
+}
+
+// ResumeRPC resumes delivery of callback invocations for a call previously
+// paused with PauseRPC.
+func (l *LndMobile) ResumeRPC(sessionID string, requestID string) {
+	l.reg.ResumeRPC(sessionID, requestID)
+}
+
+// SubscribeState watches the remote node's StateService and invokes
+// cb.OnResponse with the state name every time the node moves through
+// LOCKED, UNLOCKING and RPC_ACTIVE.
+func (l *LndMobile) SubscribeState(sessionID string, cb Callback) error {
+	return l.reg.SubscribeState(sessionID, func(state string, err error) {
+		if err != nil {
+			cb.OnError(err.Error())
+			return
+		}
+		cb.OnResponse([]byte(state))
+	})
+}
+
+// GetMetricsSnapshot returns a JSON blob of the metrics gathered so far.
+func (l *LndMobile) GetMetricsSnapshot() ([]byte, error) {
+	return json.Marshal(l.reg.Metrics.Snapshot())
+}
+
+// SubscribeErrors delivers a structured JSON error to cb.OnResponse every
+// time an RPC call panics, as an out-of-band stream the host app can use
+// for crash telemetry.
+func (l *LndMobile) SubscribeErrors(cb Callback) {
+	l.reg.SubscribeErrors(func(callErr registry.CallError) {
+		errJSON, err := json.Marshal(callErr)
+		if err != nil {
+			cb.OnError(err.Error())
+			return
+		}
+		cb.OnResponse(errJSON)
+	})
+}
+
+// StringList is a gomobile-bindable wrapper around []string, since
+// exported methods can't return a bare slice.
+type StringList struct {
+	items []string
+}
+
+// Len returns the number of items in the list.
+func (s *StringList) Len() int {
+	return len(s.items)
+}
+
+// Get returns the item at index i, or an error if i is out of range.
+func (s *StringList) Get(i int) (string, error) {
+	if i < 0 || i >= len(s.items) {
+		return "", fmt.Errorf("index %d out of range [0, %d)", i, len(s.items))
+	}
+	return s.items[i], nil
+}
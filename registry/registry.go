@@ -0,0 +1,511 @@
+// Package registry holds the platform-neutral core of the lightning-node-
+// connect client: the declared list of LND/Loop/Pool subservers, the
+// per-session JSON callback bookkeeping, and the metrics recorder. It is
+// wrapped by a thin, platform-specific entrypoint for each build target -
+// cmd/wasm-client's main_wasm.go for WASM, mobile/main_mobile.go for
+// gomobile - so the subserver list and session handling are declared once
+// and reused by both.
+package registry
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/lightninglabs/lightning-node-connect/metrics"
+	"github.com/lightninglabs/loop/looprpc"
+	"github.com/lightninglabs/pool/poolrpc"
+	"github.com/lightningnetwork/lnd/lnrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/autopilotrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/chainrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/invoicesrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/routerrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/signrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/verrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/walletrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/walletunlockerrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/watchtowerrpc"
+	"github.com/lightningnetwork/lnd/lnrpc/wtclientrpc"
+	"google.golang.org/grpc"
+)
+
+// jsonCallbackMethod is the signature every generated RegisterXxxJSONCallbacks
+// function registers its RPCs under: a JSON request in, a JSON response (or
+// error) delivered through a callback.
+type jsonCallbackMethod func(context.Context, *grpc.ClientConn, string,
+	func(string, error))
+
+type stubPackageRegistration func(map[string]jsonCallbackMethod)
+
+// Registrations is the single declared list of subserver JSON callback
+// registries, shared by every build target.
+var Registrations = []stubPackageRegistration{
+	lnrpc.RegisterLightningJSONCallbacks,
+	lnrpc.RegisterStateJSONCallbacks,
+	autopilotrpc.RegisterAutopilotJSONCallbacks,
+	chainrpc.RegisterChainNotifierJSONCallbacks,
+	invoicesrpc.RegisterInvoicesJSONCallbacks,
+	routerrpc.RegisterRouterJSONCallbacks,
+	signrpc.RegisterSignerJSONCallbacks,
+	verrpc.RegisterVersionerJSONCallbacks,
+	walletrpc.RegisterWalletKitJSONCallbacks,
+	watchtowerrpc.RegisterWatchtowerJSONCallbacks,
+	wtclientrpc.RegisterWatchtowerClientJSONCallbacks,
+	walletunlockerrpc.RegisterWalletUnlockerJSONCallbacks,
+	looprpc.RegisterSwapClientJSONCallbacks,
+	poolrpc.RegisterTraderJSONCallbacks,
+}
+
+// DialFunc opens the mailbox-tunneled gRPC connection to the remote node.
+// Each platform entrypoint supplies its own implementation, since the
+// transport used to reach the mailbox server differs (a browser WebSocket
+// for WASM, a native socket for gomobile).
+type DialFunc func(mailboxServer string, pairingPhrase string) (*grpc.ClientConn, error)
+
+// session represents a single mailbox connection to a remote node, keyed by
+// a session ID that is handed out to the caller on connect.
+type session struct {
+	conn *grpc.ClientConn
+
+	// mailboxServer and pairingPhrase are kept around so Reconnect can
+	// redial the same mailbox without the caller needing to hand them
+	// back in.
+	mailboxServer string
+	pairingPhrase string
+
+	callsMtx sync.Mutex
+	calls    map[string]*rpcCall
+}
+
+// rpcCall tracks the cancellation and pause state of a single in-flight
+// InvokeRPC call so that a caller can cancel or throttle it later by
+// request ID.
+type rpcCall struct {
+	cancel context.CancelFunc
+
+	mu     sync.Mutex
+	paused bool
+	resume chan struct{}
+}
+
+// waitIfPaused blocks the calling goroutine while the call is paused, until
+// either it is resumed or the call's context is canceled.
+func (c *rpcCall) waitIfPaused(ctx context.Context) {
+	c.mu.Lock()
+	resume := c.resume
+	paused := c.paused
+	c.mu.Unlock()
+
+	if !paused {
+		return
+	}
+
+	select {
+	case <-resume:
+	case <-ctx.Done():
+	}
+}
+
+func (c *rpcCall) pause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.paused {
+		c.paused = true
+		c.resume = make(chan struct{})
+	}
+}
+
+func (c *rpcCall) unpause() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.paused {
+		c.paused = false
+		close(c.resume)
+	}
+}
+
+// CallError is the structured representation of a panic recovered from an
+// in-flight RPC call, reported out-of-band to anyone subscribed through
+// Registry.SubscribeErrors.
+type CallError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Stack   string `json:"stack"`
+	RPCName string `json:"rpc_name"`
+}
+
+// Registry is the platform-neutral core shared by every build target: it
+// owns the per-subserver JSON callback methods, the session registry, and
+// the metrics recorder.
+type Registry struct {
+	dial    DialFunc
+	methods map[string]jsonCallbackMethod
+	Metrics metrics.Recorder
+
+	sessionsMtx sync.Mutex
+	sessions    map[string]*session
+
+	errorSubsMtx sync.Mutex
+	errorSubs    []func(CallError)
+}
+
+// New builds a Registry that dials new mailbox connections with dial.
+func New(dial DialFunc) *Registry {
+	methods := make(map[string]jsonCallbackMethod)
+	for _, registration := range Registrations {
+		registration(methods)
+	}
+
+	return &Registry{
+		dial:     dial,
+		methods:  methods,
+		Metrics:  metrics.New(),
+		sessions: make(map[string]*session),
+	}
+}
+
+// SubscribeErrors registers callback to receive every CallError recovered
+// from a panicking RPC call, as an out-of-band stream a caller can use for
+// crash telemetry.
+func (r *Registry) SubscribeErrors(callback func(CallError)) {
+	r.errorSubsMtx.Lock()
+	defer r.errorSubsMtx.Unlock()
+	r.errorSubs = append(r.errorSubs, callback)
+}
+
+// emitError fans a CallError out to every subscriber registered through
+// SubscribeErrors.
+func (r *Registry) emitError(callErr CallError) {
+	r.errorSubsMtx.Lock()
+	subs := make([]func(CallError), len(r.errorSubs))
+	copy(subs, r.errorSubs)
+	r.errorSubsMtx.Unlock()
+
+	for _, sub := range subs {
+		sub(callErr)
+	}
+}
+
+// Connect opens a new mailbox connection and registers it under a freshly
+// allocated session ID, which is returned to the caller so that every other
+// method can be routed to the right connection.
+func (r *Registry) Connect(mailboxServer string, pairingPhrase string) (string, error) {
+	handshakeStart := time.Now()
+	conn, err := r.dial(mailboxServer, pairingPhrase)
+	if err != nil {
+		return "", err
+	}
+	r.Metrics.RecordHandshake(time.Since(handshakeStart))
+
+	sessionID, err := genID()
+	if err != nil {
+		return "", err
+	}
+
+	r.sessionsMtx.Lock()
+	r.sessions[sessionID] = &session{
+		conn:          conn,
+		mailboxServer: mailboxServer,
+		pairingPhrase: pairingPhrase,
+		calls:         make(map[string]*rpcCall),
+	}
+	r.sessionsMtx.Unlock()
+
+	return sessionID, nil
+}
+
+// Reconnect redials the mailbox server for an existing session, replacing
+// its connection and canceling any RPC calls that were still in flight on
+// the old one. Callers are expected to invoke this after a failed RPC call
+// indicates the mailbox connection was dropped; IsConnected does not detect
+// a dropped connection.
+func (r *Registry) Reconnect(sessionID string) error {
+	sess := r.getSession(sessionID)
+	if sess == nil {
+		return fmt.Errorf("no session with ID %s", sessionID)
+	}
+
+	conn, err := r.dial(sess.mailboxServer, sess.pairingPhrase)
+	if err != nil {
+		return err
+	}
+
+	sess.callsMtx.Lock()
+	for _, call := range sess.calls {
+		call.cancel()
+	}
+	sess.callsMtx.Unlock()
+
+	// Swap in a fresh session rather than mutating sess.conn in place,
+	// since sess.conn is read without a lock by InvokeRPC/SubscribeState
+	// goroutines already running against the old session.
+	r.sessionsMtx.Lock()
+	r.sessions[sessionID] = &session{
+		conn:          conn,
+		mailboxServer: sess.mailboxServer,
+		pairingPhrase: sess.pairingPhrase,
+		calls:         make(map[string]*rpcCall),
+	}
+	r.sessionsMtx.Unlock()
+
+	sess.conn.Close()
+	r.Metrics.RecordReconnect()
+
+	return nil
+}
+
+// IsConnected reports whether sessionID refers to a registered session. It
+// does not check the state of the session's gRPC connection, so a session
+// whose mailbox connection was dropped still reports true.
+func (r *Registry) IsConnected(sessionID string) bool {
+	sess := r.getSession(sessionID)
+	return sess != nil && sess.conn != nil
+}
+
+// Disconnect closes and removes the mailbox connection identified by
+// sessionID, canceling any RPC calls still in flight on it.
+func (r *Registry) Disconnect(sessionID string) error {
+	r.sessionsMtx.Lock()
+	sess, ok := r.sessions[sessionID]
+	if ok {
+		delete(r.sessions, sessionID)
+	}
+	r.sessionsMtx.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	sess.callsMtx.Lock()
+	for _, call := range sess.calls {
+		call.cancel()
+	}
+	sess.callsMtx.Unlock()
+
+	return sess.conn.Close()
+}
+
+// DisconnectAll tears down every open mailbox connection. Platform
+// entrypoints call this on shutdown so a crashing or exiting process
+// doesn't leave any of them dangling.
+func (r *Registry) DisconnectAll() {
+	r.sessionsMtx.Lock()
+	ids := make([]string, 0, len(r.sessions))
+	for id := range r.sessions {
+		ids = append(ids, id)
+	}
+	r.sessionsMtx.Unlock()
+
+	for _, id := range ids {
+		r.Disconnect(id)
+	}
+}
+
+// ListSessions returns the IDs of all currently registered sessions,
+// allowing a caller to recover its view of open connections after e.g. a
+// page reload snapshotted WASM memory, or an app restart on mobile.
+func (r *Registry) ListSessions() []string {
+	r.sessionsMtx.Lock()
+	defer r.sessionsMtx.Unlock()
+
+	ids := make([]string, 0, len(r.sessions))
+	for id := range r.sessions {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// InvokeRPC starts the named RPC on the given session and returns a request
+// ID synchronously so a caller can later cancel or pause/resume the call
+// through CancelRPC/PauseRPC/ResumeRPC. This matters most for
+// server-streaming calls such as SubscribeInvoices, which would otherwise
+// only ever stop when the whole mailbox connection is torn down.
+func (r *Registry) InvokeRPC(sessionID string, rpcName string, requestJSON string,
+	callback func(resultJSON string, err error)) (string, error) {
+
+	sess := r.getSession(sessionID)
+	if sess == nil {
+		return "", fmt.Errorf("no session with ID %s", sessionID)
+	}
+
+	method, ok := r.methods[rpcName]
+	if !ok {
+		return "", fmt.Errorf("rpc with name %s not found", rpcName)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	call := &rpcCall{cancel: cancel}
+
+	requestID, err := genID()
+	if err != nil {
+		cancel()
+		return "", err
+	}
+
+	sess.callsMtx.Lock()
+	sess.calls[requestID] = call
+	sess.callsMtx.Unlock()
+
+	// Every call is counted as an open stream until its method returns,
+	// unary calls included, so OpenStreams counts all in-flight calls.
+	r.Metrics.RecordStreamOpen()
+
+	go func() {
+		start := time.Now()
+		defer func() {
+			cancel()
+			r.Metrics.RecordStreamClose()
+
+			sess.callsMtx.Lock()
+			delete(sess.calls, requestID)
+			sess.callsMtx.Unlock()
+		}()
+		defer func() {
+			if p := recover(); p != nil {
+				callErr := CallError{
+					Code:    "panic",
+					Message: fmt.Sprint(p),
+					Stack:   string(debug.Stack()),
+					RPCName: rpcName,
+				}
+				r.emitError(callErr)
+				callback("", fmt.Errorf("panic handling rpc %s: %v",
+					rpcName, p))
+			}
+		}()
+
+		cb := func(resultJSON string, err error) {
+			call.waitIfPaused(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+
+			r.Metrics.RecordCall(
+				rpcName, time.Since(start), err, len(requestJSON),
+				len(resultJSON),
+			)
+
+			callback(resultJSON, err)
+		}
+		method(ctx, sess.conn, requestJSON, cb)
+	}()
+
+	return requestID, nil
+}
+
+// CancelRPC cancels the context of a previously started RPC call, stopping
+// any further callback invocations for it.
+func (r *Registry) CancelRPC(sessionID string, requestID string) {
+	call := r.getCall(sessionID, requestID)
+	if call == nil {
+		return
+	}
+	call.cancel()
+}
+
+// PauseRPC stops delivery of further callback invocations for a streaming
+// RPC until ResumeRPC is called, giving a slow consumer a way to apply
+// backpressure instead of being flooded.
+func (r *Registry) PauseRPC(sessionID string, requestID string) {
+	call := r.getCall(sessionID, requestID)
+	if call == nil {
+		return
+	}
+	call.pause()
+}
+
+// ResumeRPC resumes delivery of callback invocations for a call previously
+// paused with PauseRPC.
+func (r *Registry) ResumeRPC(sessionID string, requestID string) {
+	call := r.getCall(sessionID, requestID)
+	if call == nil {
+		return
+	}
+	call.unpause()
+}
+
+// SubscribeState watches the remote node's StateService and invokes
+// callback with the state name every time the node moves through LOCKED,
+// UNLOCKING and RPC_ACTIVE, so a caller knows when the main lnrpc.Lightning
+// service (as opposed to the WalletUnlocker) is ready to be called.
+func (r *Registry) SubscribeState(sessionID string, callback func(state string, err error)) error {
+	sess := r.getSession(sessionID)
+	if sess == nil {
+		return fmt.Errorf("no session with ID %s", sessionID)
+	}
+
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				callErr := CallError{
+					Code:    "panic",
+					Message: fmt.Sprint(p),
+					Stack:   string(debug.Stack()),
+					RPCName: "SubscribeState",
+				}
+				r.emitError(callErr)
+				callback("", fmt.Errorf("panic handling rpc "+
+					"SubscribeState: %v", p))
+			}
+		}()
+
+		client := lnrpc.NewStateClient(sess.conn)
+		stream, err := client.SubscribeState(
+			context.Background(), &lnrpc.SubscribeStateRequest{},
+		)
+		if err != nil {
+			callback("", err)
+			return
+		}
+
+		for {
+			resp, err := stream.Recv()
+			if err != nil {
+				callback("", err)
+				return
+			}
+
+			callback(resp.State.String(), nil)
+		}
+	}()
+
+	return nil
+}
+
+// getCall looks up an in-flight RPC call by session and request ID,
+// returning nil if either doesn't exist.
+func (r *Registry) getCall(sessionID string, requestID string) *rpcCall {
+	sess := r.getSession(sessionID)
+	if sess == nil {
+		return nil
+	}
+
+	sess.callsMtx.Lock()
+	defer sess.callsMtx.Unlock()
+	return sess.calls[requestID]
+}
+
+// getSession looks up a registered session by ID, returning nil if it
+// doesn't exist.
+func (r *Registry) getSession(sessionID string) *session {
+	r.sessionsMtx.Lock()
+	defer r.sessionsMtx.Unlock()
+	return r.sessions[sessionID]
+}
+
+// genID creates a random, URL-safe identifier, used for both session and
+// request IDs.
+func genID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
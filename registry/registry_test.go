@@ -0,0 +1,170 @@
+package registry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+func TestRPCCallPauseResume(t *testing.T) {
+	ctx := context.Background()
+	call := &rpcCall{cancel: func() {}}
+
+	call.pause()
+
+	done := make(chan struct{})
+	go func() {
+		call.waitIfPaused(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("waitIfPaused returned before the call was resumed")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	call.unpause()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("waitIfPaused did not return after unpause")
+	}
+}
+
+// TestRPCCallPauseCancelRace makes sure a paused call unblocks as soon as
+// its context is canceled, even if it is never explicitly resumed - this is
+// what lets CancelRPC stop a call that JS has paused and forgotten about.
+func TestRPCCallPauseCancelRace(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	call := &rpcCall{cancel: cancel}
+
+	call.pause()
+
+	done := make(chan struct{})
+	go func() {
+		call.waitIfPaused(ctx)
+		close(done)
+	}()
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("waitIfPaused did not return after the context was canceled")
+	}
+}
+
+func fakeDial(mailboxServer string, pairingPhrase string) (*grpc.ClientConn, error) {
+	// grpc.Dial doesn't connect eagerly, so this never touches the
+	// network and is safe to use for bookkeeping-only tests.
+	return grpc.Dial("localhost:0", grpc.WithInsecure())
+}
+
+func TestSessionBookkeeping(t *testing.T) {
+	reg := New(fakeDial)
+
+	sessionID, err := reg.Connect("mailbox.example.com", "pairing phrase")
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+
+	if !reg.IsConnected(sessionID) {
+		t.Fatalf("expected session %s to be connected", sessionID)
+	}
+
+	sessions := reg.ListSessions()
+	if len(sessions) != 1 || sessions[0] != sessionID {
+		t.Fatalf("expected ListSessions to return [%s], got %v",
+			sessionID, sessions)
+	}
+
+	if err := reg.Disconnect(sessionID); err != nil {
+		t.Fatalf("Disconnect failed: %v", err)
+	}
+
+	if reg.IsConnected(sessionID) {
+		t.Fatalf("expected session %s to be disconnected", sessionID)
+	}
+	if len(reg.ListSessions()) != 0 {
+		t.Fatalf("expected no sessions after disconnect")
+	}
+}
+
+// TestInvokeRPCCleansUpAfterCompletedCall makes sure a call whose method
+// returns on its own (an ordinary unary RPC, or a stream that finishes by
+// itself) is cleaned up without anyone calling CancelRPC - regressing to
+// waiting on <-ctx.Done() after method returns would leak the goroutine and
+// the sess.calls entry forever for calls nobody explicitly cancels.
+func TestInvokeRPCCleansUpAfterCompletedCall(t *testing.T) {
+	reg := New(fakeDial)
+
+	sessionID, err := reg.Connect("mailbox.example.com", "pairing phrase")
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+
+	const rpcName = "FakeUnaryCall"
+	reg.methods[rpcName] = func(ctx context.Context, conn *grpc.ClientConn,
+		requestJSON string, cb func(string, error)) {
+
+		// A real unary (or naturally-finishing streaming) RPC method
+		// invokes cb and then returns on its own, without anyone
+		// canceling ctx.
+		cb("{}", nil)
+	}
+
+	responses := make(chan string, 1)
+	requestID, err := reg.InvokeRPC(sessionID, rpcName, "{}",
+		func(resultJSON string, err error) {
+			responses <- resultJSON
+		},
+	)
+	if err != nil {
+		t.Fatalf("InvokeRPC failed: %v", err)
+	}
+
+	select {
+	case <-responses:
+	case <-time.After(time.Second):
+		t.Fatal("callback was never invoked")
+	}
+
+	require := func() bool {
+		sess := reg.getSession(sessionID)
+		sess.callsMtx.Lock()
+		defer sess.callsMtx.Unlock()
+		_, ok := sess.calls[requestID]
+		return !ok
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if require() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("sess.calls entry was never cleaned up after the call completed")
+}
+
+func TestInvokeRPCUnknownSessionAndMethod(t *testing.T) {
+	reg := New(fakeDial)
+
+	if _, err := reg.InvokeRPC("no-such-session", "Foo", "{}", nil); err == nil {
+		t.Fatal("expected an error for an unknown session")
+	}
+
+	sessionID, err := reg.Connect("mailbox.example.com", "pairing phrase")
+	if err != nil {
+		t.Fatalf("Connect failed: %v", err)
+	}
+
+	if _, err := reg.InvokeRPC(sessionID, "NoSuchMethod", "{}", nil); err == nil {
+		t.Fatal("expected an error for an unknown RPC name")
+	}
+}